@@ -1,20 +1,17 @@
 package main
 
 import (
-	_ "embed"
 	"fmt"
 	"image"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime/cgo"
-	"strings"
+	"sync"
 	"unsafe"
 
 	"golang.org/x/image/bmp"
 
-	jumpboot "github.com/richinsley/jumpboot/pkg"
+	"github.com/richinsley/shadertoy-arcana/go/pkg/shadertoy"
 )
 
 /*
@@ -23,199 +20,116 @@ import (
 */
 import "C"
 
-type ShadertoyContext struct {
-	Repl   *jumpboot.REPLPythonProcess
-	Width  int
-	Height int
-	SHM    *jumpboot.SharedMemory
-	Shape  []int
-}
-
-//go:embed modules/shadertoyinterop.py
-var shadertoyinterop string
-
-// This is a global environment that we will use to run our Python code
-var environment *jumpboot.Environment = nil
-
 //export generatePythonEnv
 func generatePythonEnv() {
-	if environment == nil {
-		// Specify the binary folder to place micromamba in
-		cwd, _ := os.Getwd()
-		rootDirectory := filepath.Join(cwd, "..", "environments")
-		fmt.Println("Creating Jumpboot Python 3.12 repo at: ", rootDirectory)
-		version := "3.12"
-		var err error
-		environment, err = jumpboot.CreateEnvironmentMamba("shadertoy"+version, rootDirectory, version, "conda-forge", nil)
-		if err != nil {
-			fmt.Printf("Error creating environment: %v\n", err)
-			return
-		}
-		fmt.Printf("Created environment: %s\n", environment.Name)
-
-		if environment.IsNew {
-			// install our depencies
-			fmt.Println("Created a new environment... installing dependencies")
-			packages := []string{
-				"numba",
-				"numpy",
-				"glfw",
-				"wgpu-shadertoy@git+https://github.com/pygfx/shadertoy.git",
-			}
-			environment.PipInstallPackages(packages, "", "", false, nil)
-		}
+	if err := shadertoy.EnsureEnvironment(); err != nil {
+		fmt.Printf("Error creating environment: %v\n", err)
 	}
 }
 
 //export closeShadertoyContext
 func closeShadertoyContext(ctx uint64) {
-	// get the context
 	retrievedHandle := cgo.Handle(uintptr(ctx))
-	c, ok := retrievedHandle.Value().(*ShadertoyContext)
+	r, ok := retrievedHandle.Value().(*shadertoy.Renderer)
 	if !ok {
-		fmt.Println("Failed to retrieve ShadertoyContext")
+		fmt.Println("Failed to retrieve Renderer")
 		return
 	}
 
-	// close the shared memory in the python process - we need to close AND unlink
-	_, err := c.Repl.Execute("shm.close(); shm.unlink()", true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
-		return
+	if err := r.Close(); err != nil {
+		fmt.Printf("Error closing renderer: %v\n", err)
 	}
 
-	// close the python process
-	c.Repl.Close()
-
-	// free the handle
 	cgo.Handle(uintptr(ctx)).Delete()
 }
 
-//export createShadertoyContext
-func createShadertoyContext(width, height int, shaderid *C.char) uint64 {
-	// Convert C string to Go string
-	goShaderID := C.GoString(shaderid)
-
-	ctx := &ShadertoyContext{
-		Repl:   nil,
-		Width:  width,
-		Height: height,
-	}
+// shaderCache is the content-addressed cache consulted before re-fetching
+// shaders from the Shadertoy API, shared across every context created via
+// createShadertoyContext.
+var shaderCache *shadertoy.Cache
+var shaderCacheOnce sync.Once
+var shaderCacheErr error
 
-	// create a virtual environment from the system python and include the shadertoyinterop
-	cwd, _ := os.Getwd()
-	binpath := filepath.Join(cwd, "modules")
-	shadertoyinterop_module := jumpboot.NewModuleFromString("shadertoyinterop", filepath.Join(binpath, "shadertoyinterop.py"), shadertoyinterop)
-	repl, err := environment.NewREPLPythonProcess(nil, nil, []jumpboot.Module{*shadertoyinterop_module}, nil)
-	if err != nil {
-		fmt.Printf("Error creating REPLPythonProcess: %v\n", err)
-		return 0
-	}
-	ctx.Repl = repl
+// workerPool backs every context created via createShadertoyContext, so
+// repeated calls reuse a handful of warm interpreters instead of paying
+// full interpreter+environment setup cost per context.
+var workerPool *shadertoy.Pool
+var workerPoolOnce sync.Once
+var workerPoolErr error
 
-	// copy output from the Python script to stdout and stderr
-	go func() {
-		io.Copy(os.Stdout, repl.PythonProcess.Stdout)
-	}()
+const maxCacheBytes = 512 * 1024 * 1024 // 512MiB
 
-	go func() {
-		io.Copy(os.Stderr, repl.PythonProcess.Stderr)
-	}()
-
-	// from multiprocessing import shared_memory
-	retv, err := repl.Execute("from multiprocessing import shared_memory", true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
-		return 0
-	}
-	fmt.Println(retv)
+//export createShadertoyContext
+func createShadertoyContext(width, height int, shaderid *C.char) uint64 {
+	goShaderID := C.GoString(shaderid)
 
-	retv, err = repl.Execute("import shadertoyinterop, os", true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
+	// createShadertoyContext is the cgo export entry point, so it can be
+	// called concurrently from multiple native threads; sync.Once makes
+	// sure only one of them actually builds the shared Cache/Pool instead
+	// of racing to open index.json or spin up duplicate Python pools.
+	shaderCacheOnce.Do(func() {
+		shaderCache, shaderCacheErr = shadertoy.DefaultCache(maxCacheBytes)
+	})
+	if shaderCacheErr != nil {
+		fmt.Printf("Error opening shader cache: %v\n", shaderCacheErr)
 		return 0
 	}
-	fmt.Println(retv)
 
-	// set the SHADERTOY_KEY environment variable
-	retv, err = repl.Execute("os.environ['SHADERTOY_KEY'] = 'rt8lR1'", true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
+	workerPoolOnce.Do(func() {
+		workerPool, workerPoolErr = shadertoy.NewPool(shadertoy.PoolOptions{
+			Workers:    4,
+			Adapter:    shadertoy.DefaultAdapter,
+			QueueDepth: 16,
+		})
+	})
+	if workerPoolErr != nil {
+		fmt.Printf("Error creating worker pool: %v\n", workerPoolErr)
 		return 0
 	}
-	fmt.Println(retv)
 
-	// create a shadertoy renderer
-	// goShaderID := "XsBXWt"
-	retv, err = repl.Execute(fmt.Sprintf("renderer = shadertoyinterop.ShadertoyRenderer('%s', resolution=(%d, %d))", goShaderID, width, height), true)
+	r, err := workerPool.NewRenderer(shadertoy.Options{
+		ShaderID: goShaderID,
+		Width:    width,
+		Height:   height,
+		APIKey:   "rt8lR1",
+		Cache:    shaderCache,
+	})
 	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
-		return 0
-	}
-	if strings.HasPrefix(retv, "Traceback") {
-		// a python error occurred
-		fmt.Println(retv)
+		fmt.Printf("Error creating renderer: %v\n", err)
 		return 0
 	}
 
-	// create Shared Numpy array
-	numpy_name := "my_array"
-	shape := []int{height, width, 4}
-	shm, nsize, err := jumpboot.CreateSharedNumPyArray[uint8]("my_array", shape)
-	if err != nil {
-		log.Fatal(err)
-	}
-	ctx.SHM = shm
-	ctx.Shape = shape
-
-	// open the shared memory array
-	retv, err = repl.Execute(fmt.Sprintf("shm = shared_memory.SharedMemory(name='%s', create=False, size=%d)", numpy_name, nsize), true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
-		return 0
-	}
-	fmt.Println(retv)
-
-	// make it into a handle
-	return uint64(cgo.NewHandle(ctx))
+	return uint64(cgo.NewHandle(r))
 }
 
 //export renderShadertoy
 func renderShadertoy(ctxID uint64, time float32) uint64 {
-	// Convert uint64_t back to a cgo.Handle
 	handle := cgo.Handle(uintptr(ctxID))
 
-	// Retrieve the original *ShadertoyContext
-	c, ok := handle.Value().(*ShadertoyContext)
+	r, ok := handle.Value().(*shadertoy.Renderer)
 	if !ok {
 		fmt.Println("Invalid context handle")
 		return 0
 	}
 
-	time_float := float64(time)
-	_, err := c.Repl.Execute(fmt.Sprintf("renderer.render_to_shared_memory(shm, time_float=%.2f)", time_float), true)
+	pix, err := r.RenderBytes(float64(time), [4]float32{}, [2]int{r.Width(), r.Height()})
 	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
+		fmt.Printf("Error rendering frame: %v\n", err)
 		return 0
 	}
 
-	// Get data portion of shared memory (skip metadata) and return the pointer as a uint64
-	metadataSize := 4 + len(c.Shape)*4 + 16 + 1 // same as in CreateSharedNumPyArray
-	sptr := uintptr(c.SHM.GetPtr()) + uintptr(metadataSize)
-	rdata := uint64(sptr)
-	return rdata
+	// pix aliases the renderer's shared-memory segment, not Go-heap
+	// memory, so it's safe for the native caller to read after this call
+	// returns - unlike a pointer into an *image.RGBA's Pix slice, which
+	// the GC is free to collect or move as soon as Render returns.
+	return uint64(uintptr(unsafe.Pointer(&pix[0])))
 }
 
 func CopyToStridedBuffer(data []byte, target unsafe.Pointer, width, height, stride int) {
 	// Usage:
 	/*
 		// Assuming targetPtr is a C-allocated memory
-		metadataSize := 4 + len(shape)*4 + 16 + 1
-		data := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(shm.GetPtr()) + uintptr(metadataSize))), width*height*4)
-
-		// Copy to strided buffer
-		stride := width*4 + padding  // whatever your C buffer's stride is
-		CopyToStridedBuffer(data, targetPtr, width, height, stride)
+		CopyToStridedBuffer(img.Pix, targetPtr, width, height, stride)
 	*/
 
 	srcStride := width * 4 // 4 bytes per pixel (RGBA)
@@ -233,155 +147,51 @@ func CopyToStridedBuffer(data []byte, target unsafe.Pointer, width, height, stri
 	}
 }
 
-func SharedMemoryToRGBA(shm *jumpboot.SharedMemory, width, height int) *image.RGBA {
-	// Calculate metadata size
-	shape := []int{height, width, 4}
-	metadataSize := 4 + len(shape)*4 + 16 + 1
+// bmpSequenceSink writes each frame it receives to its own numbered BMP
+// file. It exists mainly as a minimal example Sink; real encoders should
+// prefer FFmpegSink.
+type bmpSequenceSink struct {
+	dir   string
+	count int
+}
 
-	// Create image
+func (s *bmpSequenceSink) WriteFrame(pix []byte, width, height int) error {
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	copy(img.Pix, pix)
 
-	// Get data portion and copy to image
-	data := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(shm.GetPtr())+uintptr(metadataSize))), width*height*4)
-	copy(img.Pix, data)
+	f, err := os.Create(filepath.Join(s.dir, fmt.Sprintf("frame_%03d.bmp", s.count)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-	return img
+	s.count++
+	return bmp.Encode(f, img)
 }
 
-func main() {
-	// Specify the binary folder to place micromamba in
-	cwd, _ := os.Getwd()
-	rootDirectory := filepath.Join(cwd, "..", "environments")
-	fmt.Println("Creating Jumpboot repo at: ", rootDirectory)
-	version := "3.12"
-	env, err := jumpboot.CreateEnvironmentMamba("myenv"+version, rootDirectory, version, "conda-forge", nil)
-	if err != nil {
-		fmt.Printf("Error creating environment: %v\n", err)
-		return
-	}
-	fmt.Printf("Created environment: %s\n", env.Name)
-
-	if env.IsNew {
-		// install our depencies
-		fmt.Println("Created a new environment... installing dependencies")
-		packages := []string{
-			"numba",
-			"numpy",
-			"glfw",
-			"wgpu-shadertoy@git+https://github.com/pygfx/shadertoy.git",
-		}
-		env.PipInstallPackages(packages, "", "", false, nil)
-	}
+func (s *bmpSequenceSink) Close() error { return nil }
 
+func main() {
 	width := 1920
 	height := 1080
 
-	// create a virtual environment from the system python and include the shadertoyinterop
-	binpath := filepath.Join(cwd, "modules")
-	shadertoyinterop_module := jumpboot.NewModuleFromString("shadertoyinterop", filepath.Join(binpath, "shadertoyinterop.py"), shadertoyinterop)
-	repl, _ := env.NewREPLPythonProcess(nil, nil, []jumpboot.Module{*shadertoyinterop_module}, nil)
-	defer repl.Close()
-
-	// copy output from the Python script to stdout and stderr
-	go func() {
-		io.Copy(os.Stdout, repl.PythonProcess.Stdout)
-	}()
-
-	go func() {
-		io.Copy(os.Stderr, repl.PythonProcess.Stderr)
-	}()
-
-	// from multiprocessing import shared_memory
-	retv, err := repl.Execute("from multiprocessing import shared_memory", true)
+	r, err := shadertoy.New(shadertoy.Options{
+		ShaderID: "XsBXWt",
+		Width:    width,
+		Height:   height,
+		APIKey:   "rt8lR1",
+	})
 	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
+		fmt.Printf("Error creating renderer: %v\n", err)
 		return
 	}
-	fmt.Println(retv)
+	defer r.Close()
 
-	retv, err = repl.Execute("import shadertoyinterop, os", true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
-		return
-	}
-	fmt.Println(retv)
-
-	// set the SHADERTOY_KEY environment variable
-	retv, err = repl.Execute("os.environ['SHADERTOY_KEY'] = 'rt8lR1'", true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
+	fps := 30.0
+	if err := r.RenderRange(0, 100/fps, fps, &bmpSequenceSink{dir: "."}); err != nil {
+		fmt.Printf("Error rendering range: %v\n", err)
 		return
 	}
-	fmt.Println(retv)
-
-	// create a shadertoy renderer
-	retv, err = repl.Execute("renderer = shadertoyinterop.ShadertoyRenderer('XsBXWt', resolution=(1920, 1080))", true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
-		return
-	}
-	fmt.Println(retv)
-
-	// create Shared Numpy array
-	numpy_name := "my_array"
-	shape := []int{height, width, 4}
-	shm, nsize, err := jumpboot.CreateSharedNumPyArray[uint8]("my_array", shape)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer shm.Close()
-
-	// open the shared memory array
-	retv, err = repl.Execute(fmt.Sprintf("shm = shared_memory.SharedMemory(name='%s', create=False, size=%d)", numpy_name, nsize), true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
-		return
-	}
-	fmt.Println(retv)
-
-	for i := 0; i < 100; i++ {
-		fmt.Printf("Rendering frame %d\n", i)
-		// render the shadertoy frame
-		// assume 30 fps
-		time_float := float64(i) / 30.0
-		retv, err = repl.Execute(fmt.Sprintf("renderer.render_to_shared_memory(shm, time_float=%.2f)", time_float), true)
-		if err != nil {
-			fmt.Printf("Error executing code: %v\n", err)
-			return
-		}
-		fmt.Println(retv)
-		fmt.Println("Frame rendered")
-
-		// Create an RGBA image
-		img := image.NewRGBA(image.Rect(0, 0, width, height))
-
-		// Get data portion of shared memory (skip metadata)
-		metadataSize := 4 + len(shape)*4 + 16 + 1 // same as in CreateSharedNumPyArray
-		data := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(shm.GetPtr())+uintptr(metadataSize))), width*height*4)
-
-		// Copy data to image
-		copy(img.Pix, data)
-
-		// Now you can encode and save the image if desired
-		// f, _ := os.Create(fmt.Sprintf("frame_%03d.png", i))
-		// png.Encode(f, img)
-		// f.Close()
-
-		f, _ := os.Create(fmt.Sprintf("frame_%03d.bmp", i))
-		bmp.Encode(f, img)
-		f.Close()
-	}
-
-	// close the shared memory in the python process - we need to close AND unlink
-	_, err = repl.Execute("shm.close(); shm.unlink()", true)
-	if err != nil {
-		fmt.Printf("Error executing code: %v\n", err)
-		return
-	}
-
-	// close the python process
-	repl.Close()
 
-	// all done
 	fmt.Println("Done")
 }
@@ -0,0 +1,236 @@
+package shadertoy
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache is a content-addressed, on-disk store for resolved Shadertoy
+// shader JSON, keyed by shader ID plus the render parameters that affect
+// what gets fetched (resolution, channel bindings), and for downloaded
+// iChannel media (textures, cubemap faces, video), keyed by source URL -
+// see MediaPath. It lives under the environments root alongside the
+// Python environment jumpboot manages.
+//
+// It does not cache serialized wgpu pipeline state. wgpu-shadertoy
+// doesn't currently expose a way to pull a compiled pipeline back out of
+// a Shadertoy instance through the REPL bridge, so there is nothing for
+// this package to serialize yet; recompilation cost is paid on every
+// Renderer, not just the first one per shader.
+type Cache struct {
+	root     string
+	maxBytes int64
+
+	mu    sync.Mutex
+	index cacheIndex
+}
+
+// cacheIndex is the on-disk bookkeeping Cache uses for LRU eviction. It is
+// small enough to load and rewrite wholesale on every Put/Prune.
+type cacheIndex struct {
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+type cacheEntry struct {
+	Size       int64     `json:"size"`
+	LastAccess time.Time `json:"last_access"`
+}
+
+const indexFileName = "index.json"
+
+// DefaultCache opens the content-addressed cache at its conventional
+// location, <environments root>/shadertoy-cache, next to the Python
+// environment jumpboot manages.
+func DefaultCache(maxBytes int64) (*Cache, error) {
+	cwd, _ := os.Getwd()
+	dir := filepath.Join(cwd, "..", "environments", "shadertoy-cache")
+	return NewCache(dir, maxBytes)
+}
+
+// NewCache opens (or creates) a content-addressed cache rooted at dir,
+// evicting least-recently-used entries once the cache exceeds maxBytes.
+// A maxBytes of 0 disables automatic eviction; call Prune explicitly
+// instead.
+func NewCache(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating cache dir: %w", err)
+	}
+
+	c := &Cache{root: dir, maxBytes: maxBytes}
+	if err := c.loadIndex(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Key derives a cache key from a shader ID and the render parameters that
+// change what needs to be fetched or recompiled for it.
+func Key(shaderID string, width, height int, channels [4]ChannelSource) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%d", shaderID, width, height)
+	for _, c := range channels {
+		fmt.Fprintf(h, "|%d|%s|%s|%s|%s|%s|%t|%s|%s", c.Kind, c.Buffer, c.Path, c.URL, c.CubemapPaths, c.CubemapURLs, c.VFlip, c.Filter, c.Wrap)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// mediaKey derives a cache key for a downloaded iChannel media URL. It is
+// namespaced separately from Key's shader-JSON keys so a coincidental
+// sha256 collision between the two keyspaces isn't possible.
+func mediaKey(mediaURL string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "media|%s", mediaURL)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// MediaPath returns the on-disk path to mediaURL's cached content,
+// downloading it first if this is the first time mediaURL has been
+// requested. The returned path is stable for as long as the entry isn't
+// evicted, and is suitable for handing to code (like wgpu-shadertoy) that
+// expects a local file rather than in-memory bytes.
+func (c *Cache) MediaPath(mediaURL string) (string, error) {
+	key := mediaKey(mediaURL)
+	if _, ok := c.Get(key); ok {
+		return c.path(key), nil
+	}
+
+	data, err := fetchMedia(mediaURL)
+	if err != nil {
+		return "", err
+	}
+	if err := c.Put(key, data); err != nil {
+		return "", fmt.Errorf("caching media %q: %w", mediaURL, err)
+	}
+	return c.path(key), nil
+}
+
+// Get returns the cached bytes for key, if present.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.index.Entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		delete(c.index.Entries, key)
+		return nil, false
+	}
+
+	entry.LastAccess = time.Now()
+	c.index.Entries[key] = entry
+	c.saveIndex()
+
+	return data, true
+}
+
+// Put stores data under key, replacing any existing entry, and evicts
+// least-recently-used entries if the cache now exceeds its configured
+// maxBytes.
+func (c *Cache) Put(key string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.WriteFile(c.path(key), data, 0o644); err != nil {
+		return fmt.Errorf("writing cache entry %s: %w", key, err)
+	}
+
+	c.index.Entries[key] = cacheEntry{
+		Size:       int64(len(data)),
+		LastAccess: time.Now(),
+	}
+	if err := c.saveIndex(); err != nil {
+		return err
+	}
+
+	if c.maxBytes > 0 {
+		return c.pruneLocked(c.maxBytes)
+	}
+	return nil
+}
+
+// Prune evicts least-recently-used entries until the cache's total size is
+// at or under maxBytes.
+func (c *Cache) Prune(maxBytes int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pruneLocked(maxBytes)
+}
+
+func (c *Cache) pruneLocked(maxBytes int64) error {
+	var total int64
+	for _, e := range c.index.Entries {
+		total += e.Size
+	}
+	if total <= maxBytes {
+		return nil
+	}
+
+	type keyed struct {
+		key string
+		cacheEntry
+	}
+	ordered := make([]keyed, 0, len(c.index.Entries))
+	for k, e := range c.index.Entries {
+		ordered = append(ordered, keyed{k, e})
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].LastAccess.Before(ordered[j].LastAccess)
+	})
+
+	for _, e := range ordered {
+		if total <= maxBytes {
+			break
+		}
+		if err := os.Remove(c.path(e.key)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("evicting cache entry %s: %w", e.key, err)
+		}
+		delete(c.index.Entries, e.key)
+		total -= e.Size
+	}
+
+	return c.saveIndex()
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.root, key)
+}
+
+func (c *Cache) loadIndex() error {
+	data, err := os.ReadFile(filepath.Join(c.root, indexFileName))
+	if os.IsNotExist(err) {
+		c.index = cacheIndex{Entries: map[string]cacheEntry{}}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading cache index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &c.index); err != nil {
+		return fmt.Errorf("parsing cache index: %w", err)
+	}
+	if c.index.Entries == nil {
+		c.index.Entries = map[string]cacheEntry{}
+	}
+	return nil
+}
+
+func (c *Cache) saveIndex() error {
+	data, err := json.Marshal(c.index)
+	if err != nil {
+		return fmt.Errorf("encoding cache index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(c.root, indexFileName), data, 0o644); err != nil {
+		return fmt.Errorf("writing cache index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package shadertoy
+
+import "testing"
+
+func TestCachePutGetRoundTrip(t *testing.T) {
+	c, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+
+	if err := c.Put("key1", []byte("hello")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	data, ok := c.Get("key1")
+	if !ok {
+		t.Fatalf("Get(key1): not found")
+	}
+	if string(data) != "hello" {
+		t.Fatalf("Get(key1) = %q, want %q", data, "hello")
+	}
+
+	if _, ok := c.Get("missing"); ok {
+		t.Fatalf("Get(missing): found unexpected entry")
+	}
+}
+
+func TestCachePruneEvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+
+	if err := c.Put("old", []byte("xxxxx")); err != nil {
+		t.Fatalf("Put(old): %v", err)
+	}
+	if err := c.Put("new", []byte("yyyyy")); err != nil {
+		t.Fatalf("Put(new): %v", err)
+	}
+
+	// Touch "old" so it becomes the most recently used entry and "new"
+	// is the one Prune should evict to make room.
+	if _, ok := c.Get("old"); !ok {
+		t.Fatalf("Get(old): not found")
+	}
+
+	if err := c.Prune(5); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+
+	if _, ok := c.Get("old"); !ok {
+		t.Errorf("Get(old): evicted, want kept (most recently used)")
+	}
+	if _, ok := c.Get("new"); ok {
+		t.Errorf("Get(new): kept, want evicted (least recently used)")
+	}
+}
+
+func TestCachePutEvictsOnOverflow(t *testing.T) {
+	c, err := NewCache(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+
+	if err := c.Put("a", []byte("aaaaa")); err != nil {
+		t.Fatalf("Put(a): %v", err)
+	}
+	if err := c.Put("b", []byte("bbbbb")); err != nil {
+		t.Fatalf("Put(b): %v", err)
+	}
+	// Pushes total size to 15 against a 10-byte budget; Put must prune
+	// automatically, evicting "a" as the least recently used entry.
+	if err := c.Put("c", []byte("ccccc")); err != nil {
+		t.Fatalf("Put(c): %v", err)
+	}
+
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get(a): kept, want evicted by Put's automatic prune")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Errorf("Get(b): evicted, want kept")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Errorf("Get(c): evicted, want kept")
+	}
+}
@@ -0,0 +1,92 @@
+package shadertoy
+
+// BufferPass identifies one of the four Shadertoy render-buffer passes that
+// can feed back into a channel, mirroring the "Buf A".."Buf D" tabs on
+// shadertoy.com.
+type BufferPass string
+
+const (
+	BufferA BufferPass = "A"
+	BufferB BufferPass = "B"
+	BufferC BufferPass = "C"
+	BufferD BufferPass = "D"
+)
+
+// ChannelKind selects which kind of iChannel input a ChannelSource supplies.
+type ChannelKind int
+
+const (
+	// ChannelNone leaves the channel unbound.
+	ChannelNone ChannelKind = iota
+	// ChannelBuffer binds the channel to the output of another pass in this
+	// Renderer's graph (Buffer A/B/C/D).
+	ChannelBuffer
+	// ChannelTexture binds the channel to a static 2D image file.
+	ChannelTexture
+	// ChannelCubemap binds the channel to a 6-sided cubemap.
+	ChannelCubemap
+	// ChannelVideo binds the channel to a decoded video file, advanced by
+	// the render time.
+	ChannelVideo
+	// ChannelKeyboard binds the channel to the 256x3 Shadertoy keyboard
+	// texture (key down/pressed/toggled rows).
+	ChannelKeyboard
+	// ChannelMouse binds the channel to the iMouse-style mouse uniform
+	// texture rather than the plain vec4 uniform.
+	ChannelMouse
+)
+
+// Wrap is the texture wrap mode applied to a sampled channel.
+type Wrap string
+
+const (
+	WrapRepeat Wrap = "repeat"
+	WrapClamp  Wrap = "clamp"
+	WrapMirror Wrap = "mirror"
+)
+
+// Filter is the texture sampling filter applied to a sampled channel.
+type Filter string
+
+const (
+	FilterLinear  Filter = "linear"
+	FilterNearest Filter = "nearest"
+	FilterMipmap  Filter = "mipmap"
+)
+
+// ChannelSource describes what feeds one of a pass's four iChannel inputs.
+// It covers the input set wgpu-shadertoy supports: the other buffer passes,
+// still textures, cubemaps, video, and the keyboard/mouse uniform textures.
+type ChannelSource struct {
+	Kind ChannelKind
+
+	// Buffer is the pass this channel reads from when Kind is
+	// ChannelBuffer.
+	Buffer BufferPass
+
+	// Path is the on-disk path to the media backing a texture or video
+	// channel when Kind is ChannelTexture or ChannelVideo. Leave it empty
+	// and set URL instead to have it fetched and cached automatically.
+	Path string
+
+	// URL, used only when Path is empty, is downloaded and cached by
+	// Options.Cache (see Cache.MediaPath) when this channel is passed to
+	// New or Pool.NewRenderer, and the resulting on-disk cache path is
+	// used exactly as if it had been passed as Path. Requires
+	// Options.Cache to be set. SetChannel does not resolve URL - only
+	// Options.Channels does.
+	URL string
+
+	// CubemapPaths holds the six faces (+X, -X, +Y, -Y, +Z, -Z) backing a
+	// cubemap channel when Kind is ChannelCubemap.
+	CubemapPaths [6]string
+
+	// CubemapURLs is CubemapPaths' remote counterpart: each non-empty
+	// face URL is fetched and cached the same way URL is, but only for
+	// faces whose CubemapPaths entry is empty.
+	CubemapURLs [6]string
+
+	VFlip  bool
+	Filter Filter
+	Wrap   Wrap
+}
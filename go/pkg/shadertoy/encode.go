@@ -0,0 +1,52 @@
+package shadertoy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// encodeChannels renders a set of ChannelSources as a Python list literal
+// of shadertoyinterop.Channel(...) constructor calls, suitable for splicing
+// into an Execute() call.
+func encodeChannels(srcs []ChannelSource) string {
+	parts := make([]string, len(srcs))
+	for i, s := range srcs {
+		parts[i] = encodeChannel(s)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// encodeChannel renders a single ChannelSource as a Python
+// shadertoyinterop.Channel(...) constructor call.
+func encodeChannel(s ChannelSource) string {
+	vflip := "False"
+	if s.VFlip {
+		vflip = "True"
+	}
+	switch s.Kind {
+	case ChannelBuffer:
+		return fmt.Sprintf("shadertoyinterop.Channel(kind='buffer', buffer=%q)", string(s.Buffer))
+	case ChannelTexture:
+		return fmt.Sprintf("shadertoyinterop.Channel(kind='texture', path=%q, vflip=%s, filter=%q, wrap=%q)",
+			s.Path, vflip, string(s.Filter), string(s.Wrap))
+	case ChannelCubemap:
+		return fmt.Sprintf("shadertoyinterop.Channel(kind='cubemap', cubemap_paths=%s, vflip=%s, filter=%q, wrap=%q)",
+			pyStringList(s.CubemapPaths[:]), vflip, string(s.Filter), string(s.Wrap))
+	case ChannelVideo:
+		return fmt.Sprintf("shadertoyinterop.Channel(kind='video', path=%q, vflip=%s)", s.Path, vflip)
+	case ChannelKeyboard:
+		return "shadertoyinterop.Channel(kind='keyboard')"
+	case ChannelMouse:
+		return "shadertoyinterop.Channel(kind='mouse')"
+	default:
+		return "shadertoyinterop.Channel(kind='none')"
+	}
+}
+
+func pyStringList(ss []string) string {
+	quoted := make([]string, len(ss))
+	for i, s := range ss {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
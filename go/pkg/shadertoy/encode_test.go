@@ -0,0 +1,85 @@
+package shadertoy
+
+import "testing"
+
+func TestEncodeChannel(t *testing.T) {
+	tests := []struct {
+		name string
+		src  ChannelSource
+		want string
+	}{
+		{
+			name: "none",
+			src:  ChannelSource{},
+			want: "shadertoyinterop.Channel(kind='none')",
+		},
+		{
+			name: "buffer",
+			src:  ChannelSource{Kind: ChannelBuffer, Buffer: BufferB},
+			want: `shadertoyinterop.Channel(kind='buffer', buffer="B")`,
+		},
+		{
+			name: "texture",
+			src: ChannelSource{
+				Kind: ChannelTexture, Path: "tex.png", VFlip: true,
+				Filter: FilterNearest, Wrap: WrapClamp,
+			},
+			want: `shadertoyinterop.Channel(kind='texture', path="tex.png", vflip=True, filter="nearest", wrap="clamp")`,
+		},
+		{
+			name: "cubemap",
+			src: ChannelSource{
+				Kind: ChannelCubemap,
+				CubemapPaths: [6]string{
+					"px.png", "nx.png", "py.png", "ny.png", "pz.png", "nz.png",
+				},
+				Filter: FilterLinear, Wrap: WrapRepeat,
+			},
+			want: `shadertoyinterop.Channel(kind='cubemap', cubemap_paths=["px.png", "nx.png", "py.png", "ny.png", "pz.png", "nz.png"], vflip=False, filter="linear", wrap="repeat")`,
+		},
+		{
+			name: "video",
+			src:  ChannelSource{Kind: ChannelVideo, Path: "clip.mp4", VFlip: true},
+			want: `shadertoyinterop.Channel(kind='video', path="clip.mp4", vflip=True)`,
+		},
+		{
+			name: "keyboard",
+			src:  ChannelSource{Kind: ChannelKeyboard},
+			want: "shadertoyinterop.Channel(kind='keyboard')",
+		},
+		{
+			name: "mouse",
+			src:  ChannelSource{Kind: ChannelMouse},
+			want: "shadertoyinterop.Channel(kind='mouse')",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeChannel(tt.src); got != tt.want {
+				t.Errorf("encodeChannel(%+v) = %s, want %s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeChannelQuotesEmbeddedQuotes(t *testing.T) {
+	src := ChannelSource{Kind: ChannelTexture, Path: `evil".path`}
+	got := encodeChannel(src)
+	want := `shadertoyinterop.Channel(kind='texture', path="evil\".path", vflip=False, filter="", wrap="")`
+	if got != want {
+		t.Errorf("encodeChannel(%+v) = %s, want %s", src, got, want)
+	}
+}
+
+func TestEncodeChannels(t *testing.T) {
+	srcs := []ChannelSource{
+		{Kind: ChannelNone},
+		{Kind: ChannelKeyboard},
+	}
+	got := encodeChannels(srcs)
+	want := "[shadertoyinterop.Channel(kind='none'), shadertoyinterop.Channel(kind='keyboard')]"
+	if got != want {
+		t.Errorf("encodeChannels(%+v) = %s, want %s", srcs, got, want)
+	}
+}
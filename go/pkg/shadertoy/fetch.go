@@ -0,0 +1,60 @@
+package shadertoy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const shadertoyAPIBase = "https://www.shadertoy.com/api/v1/shaders/"
+
+// fetchShaderJSON downloads the raw shader definition for shaderID from
+// the Shadertoy API using apiKey, returning the response body as-is so it
+// can be cached verbatim.
+func fetchShaderJSON(shaderID, apiKey string) ([]byte, error) {
+	reqURL, err := url.Parse(shadertoyAPIBase + url.PathEscape(shaderID))
+	if err != nil {
+		return nil, fmt.Errorf("building shader %q URL: %w", shaderID, err)
+	}
+	q := reqURL.Query()
+	q.Set("key", apiKey)
+	reqURL.RawQuery = q.Encode()
+
+	resp, err := http.Get(reqURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("fetching shader %q: %w", shaderID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching shader %q: unexpected status %s", shaderID, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading shader %q response: %w", shaderID, err)
+	}
+	return body, nil
+}
+
+// fetchMedia downloads arbitrary iChannel media (a texture, cubemap face,
+// or video) from mediaURL, returning the raw bytes as-is so Cache.MediaPath
+// can store them verbatim.
+func fetchMedia(mediaURL string) ([]byte, error) {
+	resp, err := http.Get(mediaURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching media %q: %w", mediaURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching media %q: unexpected status %s", mediaURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading media %q response: %w", mediaURL, err)
+	}
+	return body, nil
+}
@@ -0,0 +1,202 @@
+package shadertoy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	jumpboot "github.com/richinsley/jumpboot/pkg"
+)
+
+// AdapterSelector picks which wgpu adapter a pool worker's interpreter
+// renders with.
+type AdapterSelector struct {
+	// Index is the position of the desired adapter in
+	// wgpu.gpu.enumerate_adapters_sync(). A negative Index (-1, as in
+	// DefaultAdapter, is the conventional "don't care") leaves adapter
+	// selection to wgpu's own default/power-preference heuristic. The
+	// zero value, 0, pins the first enumerated adapter.
+	Index int
+}
+
+// DefaultAdapter leaves adapter selection to wgpu's own heuristics.
+var DefaultAdapter = AdapterSelector{Index: -1}
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	// Workers is the number of Python worker processes to keep warm.
+	Workers int
+
+	// Adapter selects the GPU adapter every worker in the pool pins to.
+	Adapter AdapterSelector
+
+	// QueueDepth bounds how many callers may block in acquire waiting for
+	// a free worker: once more than Workers+QueueDepth callers are waiting
+	// at the same time, NewRenderer fails fast with a backpressure error
+	// instead of blocking further. Callers already holding a worker are
+	// not counted.
+	QueueDepth int
+}
+
+// poolWorker is one warm Python interpreter, pinned to an adapter,
+// identified by id for log messages.
+type poolWorker struct {
+	id   int
+	repl *jumpboot.REPLPythonProcess
+}
+
+// Pool owns a fixed number of Python worker processes and dispatches
+// Renderer creation across them, so batch workloads pay interpreter and
+// environment setup cost once per worker instead of once per shader.
+type Pool struct {
+	opts PoolOptions
+	free chan *poolWorker
+
+	waiting int32
+	nextID  int32
+}
+
+// NewPool starts opts.Workers Python interpreters, each pinned to
+// opts.Adapter, and returns a Pool ready to hand out Renderers.
+func NewPool(opts PoolOptions) (*Pool, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if err := generatePythonEnv(); err != nil {
+		return nil, err
+	}
+
+	p := &Pool{
+		opts: opts,
+		free: make(chan *poolWorker, opts.Workers),
+	}
+
+	for i := 0; i < opts.Workers; i++ {
+		w, err := p.spawnWorker()
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.free <- w
+	}
+
+	return p, nil
+}
+
+// spawnWorker creates one REPL process with the shadertoyinterop module
+// importable and pinned to the pool's configured adapter.
+func (p *Pool) spawnWorker() (*poolWorker, error) {
+	id := int(atomic.AddInt32(&p.nextID, 1))
+
+	cwd, _ := os.Getwd()
+	binpath := filepath.Join(cwd, "modules")
+	shadertoyinterop_module := jumpboot.NewModuleFromString("shadertoyinterop", filepath.Join(binpath, "shadertoyinterop.py"), shadertoyinterop)
+	repl, err := environment.NewREPLPythonProcess(nil, nil, []jumpboot.Module{*shadertoyinterop_module}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("spawning pool worker %d: %w", id, err)
+	}
+
+	go io.Copy(os.Stdout, repl.PythonProcess.Stdout)
+	go io.Copy(os.Stderr, repl.PythonProcess.Stderr)
+
+	if err := pinAdapter(repl, p.opts.Adapter); err != nil {
+		repl.Close()
+		return nil, fmt.Errorf("pinning adapter for pool worker %d: %w", id, err)
+	}
+
+	return &poolWorker{id: id, repl: repl}, nil
+}
+
+// pinAdapter, when sel.Index is non-negative, patches the worker's
+// interpreter so every future wgpu adapter request resolves to that
+// enumerated adapter instead of wgpu's own default/power-preference
+// choice.
+func pinAdapter(repl *jumpboot.REPLPythonProcess, sel AdapterSelector) error {
+	if sel.Index < 0 {
+		return nil
+	}
+
+	code := fmt.Sprintf(`
+import wgpu
+_pinned_adapter = wgpu.gpu.enumerate_adapters_sync()[%d]
+wgpu.gpu.request_adapter_sync = lambda **kwargs: _pinned_adapter
+`, sel.Index)
+
+	retv, err := repl.Execute(code, true)
+	if err != nil {
+		return fmt.Errorf("executing code: %w", err)
+	}
+	if strings.HasPrefix(retv, "Traceback") {
+		return fmt.Errorf("selecting adapter index %d: %s", sel.Index, retv)
+	}
+	return nil
+}
+
+// acquire checks out an idle worker, applying QueueDepth backpressure: if
+// more callers are already waiting than Workers+QueueDepth allows, it
+// fails immediately instead of blocking indefinitely.
+func (p *Pool) acquire() (*poolWorker, error) {
+	if int(atomic.AddInt32(&p.waiting, 1)) > p.opts.Workers+p.opts.QueueDepth {
+		atomic.AddInt32(&p.waiting, -1)
+		return nil, fmt.Errorf("shadertoy pool queue full (workers=%d, queueDepth=%d)", p.opts.Workers, p.opts.QueueDepth)
+	}
+
+	w, ok := <-p.free
+	atomic.AddInt32(&p.waiting, -1)
+	if !ok {
+		return nil, fmt.Errorf("shadertoy pool is closed")
+	}
+	return w, nil
+}
+
+// release returns a worker to the free queue for reuse.
+func (p *Pool) release(w *poolWorker) {
+	p.free <- w
+}
+
+// recycle discards a worker whose interpreter hit a Python exception and
+// transparently spawns a replacement to take its place in the pool.
+func (p *Pool) recycle(w *poolWorker) {
+	w.repl.Close()
+	go func() {
+		nw, err := p.spawnWorker()
+		if err != nil {
+			fmt.Printf("Error respawning shadertoy pool worker %d: %v\n", w.id, err)
+			return
+		}
+		p.free <- nw
+	}()
+}
+
+// NewRenderer acquires an idle worker and builds a Renderer bound to its
+// already-warm interpreter. Closing the returned Renderer returns the
+// worker to the pool instead of tearing the interpreter down.
+func (p *Pool) NewRenderer(opts Options) (*Renderer, error) {
+	w, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newOnRepl(w.repl, opts)
+	if err != nil {
+		p.recycle(w)
+		return nil, err
+	}
+
+	r.pool = p
+	r.worker = w
+	return r, nil
+}
+
+// Close terminates every idle worker in the pool. Renderers checked out
+// via NewRenderer must be closed before calling Close.
+func (p *Pool) Close() error {
+	close(p.free)
+	for w := range p.free {
+		w.repl.Close()
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package shadertoy
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// These tests exercise Pool's backpressure bookkeeping directly, without
+// going through NewPool, since that spawns real Python interpreters.
+
+func TestPoolAcquireSucceedsUnderCapacity(t *testing.T) {
+	p := &Pool{
+		opts: PoolOptions{Workers: 1, QueueDepth: 0},
+		free: make(chan *poolWorker, 1),
+	}
+	want := &poolWorker{id: 7}
+	p.free <- want
+
+	got, err := p.acquire()
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if got != want {
+		t.Fatalf("acquire returned %v, want %v", got, want)
+	}
+	if n := atomic.LoadInt32(&p.waiting); n != 0 {
+		t.Fatalf("waiting = %d after acquire, want 0", n)
+	}
+}
+
+func TestPoolAcquireRejectsOverCapacity(t *testing.T) {
+	p := &Pool{
+		opts: PoolOptions{Workers: 1, QueueDepth: 1},
+		free: make(chan *poolWorker), // unbuffered and empty: a successful
+		// acquire would block forever, so the test only passes if the
+		// backpressure check rejects before touching this channel.
+	}
+
+	// Simulate Workers+QueueDepth callers already queued ahead of us.
+	atomic.StoreInt32(&p.waiting, int32(p.opts.Workers+p.opts.QueueDepth))
+
+	if _, err := p.acquire(); err == nil {
+		t.Fatalf("acquire: want backpressure error, got nil")
+	}
+
+	// acquire must undo its own increment on the rejected path, or every
+	// later caller would be rejected too.
+	if got, want := atomic.LoadInt32(&p.waiting), int32(p.opts.Workers+p.opts.QueueDepth); got != want {
+		t.Fatalf("waiting = %d after rejected acquire, want unchanged at %d", got, want)
+	}
+}
+
+func TestPoolRelease(t *testing.T) {
+	p := &Pool{
+		opts: PoolOptions{Workers: 1},
+		free: make(chan *poolWorker, 1),
+	}
+	w := &poolWorker{id: 3}
+	p.release(w)
+
+	select {
+	case got := <-p.free:
+		if got != w {
+			t.Fatalf("release: got %v, want %v", got, w)
+		}
+	default:
+		t.Fatalf("release: worker not queued to free channel")
+	}
+}
@@ -0,0 +1,444 @@
+// Package shadertoy is an importable Go API around the Shadertoy REPL
+// backend: it drives a Python process (via jumpboot) that resolves a
+// Shadertoy shader ID into one or more wgpu-shadertoy passes and renders
+// frames into a shared-memory buffer.
+package shadertoy
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	jumpboot "github.com/richinsley/jumpboot/pkg"
+)
+
+//go:embed modules/shadertoyinterop.py
+var shadertoyinterop string
+
+// environment is the shared Python environment used to spawn REPLs for
+// every Renderer. It is created lazily on first use.
+var environment *jumpboot.Environment
+
+// Options configures a Renderer.
+type Options struct {
+	// ShaderID is the Shadertoy shader ID to resolve (e.g. "XsBXWt").
+	ShaderID string
+
+	// Width and Height are the render target dimensions in pixels.
+	Width, Height int
+
+	// APIKey is the Shadertoy API key used to resolve ShaderID. If empty,
+	// the renderer relies on the shader already being cached on disk.
+	APIKey string
+
+	// Channels overrides the Image pass's four iChannel inputs on top of
+	// however the resolved shader JSON already wires them (Buffer A/B/C/D
+	// passes always resolve straight from that JSON, not from Channels).
+	// A ChannelNone entry leaves the corresponding slot as the JSON
+	// defined it. Use SetChannel to change an override later.
+	Channels [4]ChannelSource
+
+	// Cache, if set, is consulted for the resolved shader JSON before
+	// hitting the Shadertoy API, and populated after a successful fetch.
+	// If nil, New always fetches from the API (and requires APIKey).
+	Cache *Cache
+
+	// Offline, when true, fails New with an error instead of fetching
+	// from the Shadertoy API on a cache miss. Requires Cache to be set.
+	Offline bool
+}
+
+// Renderer wraps a single Shadertoy shader - potentially a multi-pass graph
+// of an Image pass plus Buffer A/B/C/D passes - and renders it frame by
+// frame into a shared-memory buffer.
+type Renderer struct {
+	repl   *jumpboot.REPLPythonProcess
+	width  int
+	height int
+	shm    *jumpboot.SharedMemory
+	shape  []int
+
+	// pool and worker are set when this Renderer was built by
+	// Pool.NewRenderer. Close then returns the worker to the pool
+	// instead of tearing down the interpreter.
+	pool   *Pool
+	worker *poolWorker
+	// broken marks a Renderer whose interpreter hit a Python exception
+	// mid-use; Close recycles rather than releases such a worker.
+	broken bool
+}
+
+// New resolves shaderID and spins up a fresh Python REPL to render it. The
+// returned Renderer owns a Python process and a shared-memory segment;
+// call Close when done with it. For batch workloads that create many
+// short-lived Renderers, prefer a Pool, which amortizes interpreter
+// startup across renderers instead of paying it per call.
+func New(opts Options) (*Renderer, error) {
+	if err := generatePythonEnv(); err != nil {
+		return nil, err
+	}
+
+	cwd, _ := os.Getwd()
+	binpath := filepath.Join(cwd, "modules")
+	shadertoyinterop_module := jumpboot.NewModuleFromString("shadertoyinterop", filepath.Join(binpath, "shadertoyinterop.py"), shadertoyinterop)
+	repl, err := environment.NewREPLPythonProcess(nil, nil, []jumpboot.Module{*shadertoyinterop_module}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating REPLPythonProcess: %w", err)
+	}
+
+	go io.Copy(os.Stdout, repl.PythonProcess.Stdout)
+	go io.Copy(os.Stderr, repl.PythonProcess.Stderr)
+
+	r, err := newOnRepl(repl, opts)
+	if err != nil {
+		repl.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// newOnRepl builds a Renderer around an already-running REPL, resolving
+// opts.ShaderID and binding its shared-memory output buffer. repl must
+// already have the shadertoyinterop module importable.
+func newOnRepl(repl *jumpboot.REPLPythonProcess, opts Options) (*Renderer, error) {
+	r := &Renderer{
+		repl:   repl,
+		width:  opts.Width,
+		height: opts.Height,
+	}
+
+	if _, err := repl.Execute("from multiprocessing import shared_memory", true); err != nil {
+		return nil, fmt.Errorf("executing code: %w", err)
+	}
+
+	if _, err := repl.Execute("import shadertoyinterop, os", true); err != nil {
+		return nil, fmt.Errorf("executing code: %w", err)
+	}
+
+	if opts.APIKey != "" {
+		// %q (not a hand-rolled '%s') so an APIKey containing a quote or
+		// backslash can't break out of the Python string literal.
+		if _, err := repl.Execute(fmt.Sprintf("os.environ['SHADERTOY_KEY'] = %q", opts.APIKey), true); err != nil {
+			return nil, fmt.Errorf("executing code: %w", err)
+		}
+	}
+
+	shaderJSONArg := "None"
+	if opts.Cache != nil {
+		shaderJSON, err := resolveShaderJSON(opts)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := repl.Execute(fmt.Sprintf("import base64 as _b64\n_shader_json = _b64.b64decode('%s').decode('utf-8')",
+			base64.StdEncoding.EncodeToString(shaderJSON)), true); err != nil {
+			return nil, fmt.Errorf("executing code: %w", err)
+		}
+		shaderJSONArg = "_shader_json"
+	}
+
+	channels := opts.Channels
+	if channelsNeedMediaFetch(channels) {
+		if opts.Cache == nil {
+			return nil, fmt.Errorf("channel media specified by URL requires Options.Cache to be set")
+		}
+		resolved, err := resolveChannelMedia(opts.Cache, channels)
+		if err != nil {
+			return nil, err
+		}
+		channels = resolved
+	}
+
+	channelsArg := encodeChannels(channels[:])
+	retv, err := repl.Execute(fmt.Sprintf(
+		// %q, not '%s': opts.ShaderID is caller-supplied (it crosses the
+		// cgo boundary verbatim in createShadertoyContext), so it must be
+		// quoted the same safe way encodeChannel already quotes paths.
+		"renderer = shadertoyinterop.ShadertoyRenderer(%q, resolution=(%d, %d), channels=%s, shader_json=%s)",
+		opts.ShaderID, opts.Width, opts.Height, channelsArg, shaderJSONArg), true)
+	if err != nil {
+		return nil, fmt.Errorf("executing code: %w", err)
+	}
+	if strings.HasPrefix(retv, "Traceback") {
+		return nil, fmt.Errorf("resolving shader %q: %s", opts.ShaderID, retv)
+	}
+
+	numpyName := "my_array"
+	shape := []int{opts.Height, opts.Width, 4}
+	shm, nsize, err := jumpboot.CreateSharedNumPyArray[uint8](numpyName, shape)
+	if err != nil {
+		return nil, fmt.Errorf("creating shared numpy array: %w", err)
+	}
+	r.shm = shm
+	r.shape = shape
+
+	if _, err := repl.Execute(fmt.Sprintf("shm = shared_memory.SharedMemory(name='%s', create=False, size=%d)", numpyName, nsize), true); err != nil {
+		return nil, fmt.Errorf("executing code: %w", err)
+	}
+
+	return r, nil
+}
+
+// resolveShaderJSON returns the raw Shadertoy API JSON for opts.ShaderID,
+// preferring opts.Cache over the network. On a cache miss it fetches via
+// the API (unless opts.Offline is set, in which case it fails fast) and
+// stores the result back in the cache for next time.
+func resolveShaderJSON(opts Options) ([]byte, error) {
+	key := Key(opts.ShaderID, opts.Width, opts.Height, opts.Channels)
+
+	if data, ok := opts.Cache.Get(key); ok {
+		return data, nil
+	}
+
+	if opts.Offline {
+		return nil, fmt.Errorf("shader %q not in cache and offline mode is enabled", opts.ShaderID)
+	}
+
+	data, err := fetchShaderJSON(opts.ShaderID, opts.APIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := opts.Cache.Put(key, data); err != nil {
+		return nil, fmt.Errorf("caching shader %q: %w", opts.ShaderID, err)
+	}
+
+	return data, nil
+}
+
+// channelsNeedMediaFetch reports whether any channel in channels
+// references remote media (via URL or CubemapURLs) that
+// resolveChannelMedia would need a Cache to fetch and store.
+func channelsNeedMediaFetch(channels [4]ChannelSource) bool {
+	for _, c := range channels {
+		switch c.Kind {
+		case ChannelTexture, ChannelVideo:
+			if c.Path == "" && c.URL != "" {
+				return true
+			}
+		case ChannelCubemap:
+			for i, faceURL := range c.CubemapURLs {
+				if c.CubemapPaths[i] == "" && faceURL != "" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// resolveChannelMedia downloads and caches any remote iChannel media
+// referenced by URL/CubemapURLs, filling in Path/CubemapPaths with the
+// resulting on-disk cache path so encodeChannel treats it exactly like a
+// caller-supplied local file. Channels that already carry a Path/
+// CubemapPaths entry, or that don't reference a URL, pass through
+// unchanged.
+func resolveChannelMedia(cache *Cache, channels [4]ChannelSource) ([4]ChannelSource, error) {
+	for i, c := range channels {
+		switch c.Kind {
+		case ChannelTexture, ChannelVideo:
+			if c.Path == "" && c.URL != "" {
+				path, err := cache.MediaPath(c.URL)
+				if err != nil {
+					return channels, fmt.Errorf("fetching channel %d media: %w", i, err)
+				}
+				c.Path = path
+			}
+		case ChannelCubemap:
+			for f, faceURL := range c.CubemapURLs {
+				if c.CubemapPaths[f] != "" || faceURL == "" {
+					continue
+				}
+				path, err := cache.MediaPath(faceURL)
+				if err != nil {
+					return channels, fmt.Errorf("fetching channel %d cubemap face %d: %w", i, f, err)
+				}
+				c.CubemapPaths[f] = path
+			}
+		}
+		channels[i] = c
+	}
+	return channels, nil
+}
+
+// EnsureEnvironment creates the shared Python environment used by every
+// Renderer, if it hasn't been created yet. Calling it ahead of time lets a
+// caller surface environment-setup errors before the first New call.
+func EnsureEnvironment() error {
+	return generatePythonEnv()
+}
+
+// Width returns the render target width in pixels.
+func (r *Renderer) Width() int { return r.width }
+
+// Height returns the render target height in pixels.
+func (r *Renderer) Height() int { return r.height }
+
+// SetChannel rebinds channel i (0-3) of the shader's Image pass to src.
+func (r *Renderer) SetChannel(i int, src ChannelSource) error {
+	if i < 0 || i > 3 {
+		return fmt.Errorf("channel index %d out of range [0,3]", i)
+	}
+	retv, err := r.repl.Execute(fmt.Sprintf("renderer.set_channel(%d, %s)", i, encodeChannel(src)), true)
+	if err != nil {
+		return fmt.Errorf("executing code: %w", err)
+	}
+	if strings.HasPrefix(retv, "Traceback") {
+		return fmt.Errorf("setting channel %d: %s", i, retv)
+	}
+	return nil
+}
+
+// Render advances the shader to time t (seconds), with the given mouse
+// state (x, y, clickX, clickY, matching iMouse) and output resolution, and
+// returns the rendered frame as an *image.RGBA.
+//
+// resolution must equal the Width/Height passed in Options: rendering at a
+// different size than the shared-memory buffer was allocated for is not
+// supported, and Render returns an error instead.
+func (r *Renderer) Render(t float64, mouse [4]float32, resolution [2]int) (*image.RGBA, error) {
+	if resolution[0] != r.width || resolution[1] != r.height {
+		return nil, fmt.Errorf("resolution (%d,%d) does not match renderer size (%d,%d)", resolution[0], resolution[1], r.width, r.height)
+	}
+
+	if err := r.renderToSharedMemory(t, mouse); err != nil {
+		return nil, err
+	}
+
+	return r.frame(), nil
+}
+
+// RenderBytes advances the shader to time t exactly like Render, but
+// returns a zero-copy []byte view over the shared-memory pixel data
+// instead of a fresh *image.RGBA. Unlike Render's result, the returned
+// slice aliases the OS-backed shared-memory segment rather than a
+// Go-heap allocation, so it is safe for a cgo export to hand the
+// underlying pointer to a native caller: the memory isn't subject to the
+// Go GC moving or reclaiming it once this call returns. The slice is only
+// valid until the next Render/RenderBytes call and must not be retained
+// past Close.
+func (r *Renderer) RenderBytes(t float64, mouse [4]float32, resolution [2]int) ([]byte, error) {
+	if resolution[0] != r.width || resolution[1] != r.height {
+		return nil, fmt.Errorf("resolution (%d,%d) does not match renderer size (%d,%d)", resolution[0], resolution[1], r.width, r.height)
+	}
+
+	if err := r.renderToSharedMemory(t, mouse); err != nil {
+		return nil, err
+	}
+
+	return r.framePix(), nil
+}
+
+// renderToSharedMemory advances the shader to time t with the given mouse
+// state and renders into the shared-memory buffer, without copying the
+// result out. Render and RenderRange build on this.
+func (r *Renderer) renderToSharedMemory(t float64, mouse [4]float32) error {
+	retv, err := r.repl.Execute(fmt.Sprintf(
+		"renderer.render_to_shared_memory(shm, time_float=%.6f, mouse=(%f,%f,%f,%f))",
+		t, mouse[0], mouse[1], mouse[2], mouse[3]), true)
+	if err != nil {
+		r.broken = true
+		return fmt.Errorf("executing code: %w", err)
+	}
+	if strings.HasPrefix(retv, "Traceback") {
+		r.broken = true
+		return fmt.Errorf("rendering frame: %s", retv)
+	}
+	return nil
+}
+
+// frame copies the current contents of the shared-memory buffer into a
+// fresh *image.RGBA.
+func (r *Renderer) frame() *image.RGBA {
+	metadataSize := 4 + len(r.shape)*4 + 16 + 1 // same as in CreateSharedNumPyArray
+	data := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(r.shm.GetPtr())+uintptr(metadataSize))), r.width*r.height*4)
+
+	img := image.NewRGBA(image.Rect(0, 0, r.width, r.height))
+	copy(img.Pix, data)
+	return img
+}
+
+// dataPtr returns the address of the pixel data inside the shared-memory
+// segment, skipping the numpy header. Sinks that want zero-copy access to
+// a rendered frame use this instead of frame().
+func (r *Renderer) dataPtr() unsafe.Pointer {
+	metadataSize := 4 + len(r.shape)*4 + 16 + 1
+	return unsafe.Pointer(uintptr(r.shm.GetPtr()) + uintptr(metadataSize))
+}
+
+// Close releases the shared-memory segment, on both the Python side and
+// the Go side. If this Renderer was built with New, it also terminates
+// the backing Python process; if it came from a Pool, the worker is
+// returned to the pool for reuse instead - or, if a Python exception left
+// the interpreter in a bad state, discarded and transparently replaced.
+// It is safe to call once; further use of the Renderer is invalid
+// afterwards.
+func (r *Renderer) Close() error {
+	if r.repl == nil {
+		return nil
+	}
+
+	var shmErr error
+	if !r.broken {
+		if _, err := r.repl.Execute("shm.close(); shm.unlink()", true); err != nil {
+			r.broken = true
+			shmErr = fmt.Errorf("closing shared memory: %w", err)
+		}
+	}
+
+	// Unmap the Go-side view of the segment regardless of r.broken: the
+	// Python-side close above is what unlinks the shared-memory object,
+	// but this process's own mmap/fd onto it leaks unless we close it
+	// too.
+	if err := r.shm.Close(); err != nil && shmErr == nil {
+		shmErr = fmt.Errorf("closing shared memory handle: %w", err)
+	}
+
+	if r.pool != nil {
+		if r.broken {
+			r.pool.recycle(r.worker)
+		} else {
+			r.pool.release(r.worker)
+		}
+		r.repl = nil
+		return shmErr
+	}
+
+	r.repl.Close()
+	r.repl = nil
+	return shmErr
+}
+
+// generatePythonEnv creates the shared Python environment used by every
+// Renderer, if it hasn't been created yet.
+func generatePythonEnv() error {
+	if environment != nil {
+		return nil
+	}
+
+	cwd, _ := os.Getwd()
+	rootDirectory := filepath.Join(cwd, "..", "environments")
+	version := "3.12"
+	env, err := jumpboot.CreateEnvironmentMamba("shadertoy"+version, rootDirectory, version, "conda-forge", nil)
+	if err != nil {
+		return fmt.Errorf("creating environment: %w", err)
+	}
+
+	if env.IsNew {
+		packages := []string{
+			"numba",
+			"numpy",
+			"glfw",
+			"wgpu-shadertoy@git+https://github.com/pygfx/shadertoy.git",
+		}
+		env.PipInstallPackages(packages, "", "", false, nil)
+	}
+
+	environment = env
+	return nil
+}
@@ -0,0 +1,142 @@
+package shadertoy
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"unsafe"
+)
+
+// Sink receives rendered frames one at a time. Implementations decide what
+// to do with them: write to an encoder's stdin, push to an HTTP endpoint,
+// or buffer them in memory.
+type Sink interface {
+	// WriteFrame is called once per rendered frame, in order, with the
+	// frame's packed RGBA pixels. Implementations must not retain pix
+	// past the call, since it may point directly into the renderer's
+	// shared-memory segment.
+	WriteFrame(pix []byte, width, height int) error
+
+	// Close flushes and releases any resources the sink holds open.
+	Close() error
+}
+
+// WriterSink adapts any io.Writer into a Sink by writing each frame's
+// packed RGBA bytes to it in turn. It is the simplest Sink and is useful
+// for piping raw frames into an external process's stdin.
+type WriterSink struct {
+	w io.Writer
+}
+
+// NewWriterSink wraps w as a Sink of packed RGBA frames.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) WriteFrame(pix []byte, width, height int) error {
+	_, err := s.w.Write(pix)
+	return err
+}
+
+func (s *WriterSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// FFmpegCodec selects the video codec an FFmpegSink asks ffmpeg to encode
+// with.
+type FFmpegCodec string
+
+const (
+	CodecH264   FFmpegCodec = "libx264"
+	CodecHEVC   FFmpegCodec = "libx265"
+	CodecProRes FFmpegCodec = "prores_ks"
+)
+
+// FFmpegSink pipes raw RGBA frames into an ffmpeg process's stdin and has
+// it encode them to outputPath.
+type FFmpegSink struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// NewFFmpegSink starts an ffmpeg process that reads width x height RGBA
+// frames at the given frame rate from stdin and encodes them with codec to
+// outputPath.
+func NewFFmpegSink(outputPath string, width, height int, fps float64, codec FFmpegCodec) (*FFmpegSink, error) {
+	cmd := exec.Command("ffmpeg",
+		"-y",
+		"-f", "rawvideo",
+		"-pix_fmt", "rgba",
+		"-s", fmt.Sprintf("%dx%d", width, height),
+		"-r", fmt.Sprintf("%f", fps),
+		"-i", "-",
+		"-c:v", string(codec),
+		outputPath,
+	)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("opening ffmpeg stdin: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting ffmpeg: %w", err)
+	}
+
+	return &FFmpegSink{cmd: cmd, stdin: stdin}, nil
+}
+
+func (s *FFmpegSink) WriteFrame(pix []byte, width, height int) error {
+	_, err := s.stdin.Write(pix)
+	return err
+}
+
+func (s *FFmpegSink) Close() error {
+	if err := s.stdin.Close(); err != nil {
+		return fmt.Errorf("closing ffmpeg stdin: %w", err)
+	}
+	return s.cmd.Wait()
+}
+
+// framePix returns a []byte view directly over the shared-memory pixel
+// data for the current frame, with no copy. The returned slice is only
+// valid until the next Render call.
+func (r *Renderer) framePix() []byte {
+	return unsafe.Slice((*byte)(r.dataPtr()), r.width*r.height*4)
+}
+
+// RenderRange renders frames at the given frame rate from time start to
+// end (seconds, inclusive of start, exclusive of end) and writes each one
+// to sink, handing sink a zero-copy view of the shared-memory frame data
+// where possible. sink.Close is always called before returning, even if a
+// render or write fails partway through, so a FFmpegSink's subprocess is
+// never left running on an error exit.
+func (r *Renderer) RenderRange(start, end, fps float64, sink Sink) (err error) {
+	defer func() {
+		if cerr := sink.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if fps <= 0 {
+		return fmt.Errorf("fps must be positive, got %f", fps)
+	}
+
+	dt := 1.0 / fps
+	for t := start; t < end; t += dt {
+		if rerr := r.renderToSharedMemory(t, [4]float32{}); rerr != nil {
+			err = fmt.Errorf("rendering frame at t=%.3f: %w", t, rerr)
+			return
+		}
+
+		if werr := sink.WriteFrame(r.framePix(), r.width, r.height); werr != nil {
+			err = fmt.Errorf("writing frame at t=%.3f: %w", t, werr)
+			return
+		}
+	}
+
+	return
+}